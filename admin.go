@@ -0,0 +1,37 @@
+package dynamicdns
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+// Routes returns this app's admin API endpoints.
+func (a *App) Routes() []caddy.AdminRoute {
+	return []caddy.AdminRoute{
+		{
+			Pattern: "/dynamic_dns/reconcile",
+			Handler: caddy.AdminHandlerFunc(a.handleReconcile),
+		},
+	}
+}
+
+// handleReconcile forces an immediate DNS reconciliation pass, without
+// waiting for check_interval or a config reload.
+func (a *App) handleReconcile(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return caddy.APIError{
+			HTTPStatus: http.StatusMethodNotAllowed,
+			Err:        fmt.Errorf("method not allowed"),
+		}
+	}
+
+	a.updateDNS()
+
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// Interface guards
+var _ caddy.AdminRouter = (*App)(nil)
@@ -0,0 +1,38 @@
+package dynamicdns
+
+import (
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// Backend is implemented by the various ways this module knows how to talk
+// to a Windows DNS server: over SSH with PowerShell's DnsServer cmdlets
+// (backends/ssh), over WinRM (backends/winrm), or by shelling out to the
+// legacy dnscmd.exe syntax (backends/dnscmd). The dns_server Caddyfile block
+// selects one with a `backend <name>` subdirective, and App loads it as a
+// Caddy module the same way it loads any other pluggable component.
+type Backend interface {
+	// EnsureCNAME creates or updates a CNAME record so that name points at
+	// target, with the given TTL.
+	EnsureCNAME(zone, name, target string, ttl time.Duration) error
+
+	// EnsureTXT creates or updates a TXT record so that name holds value,
+	// with the given TTL.
+	EnsureTXT(zone, name, value string, ttl time.Duration) error
+
+	// EnsureA creates or updates an A record so that name resolves to
+	// address, with the given TTL.
+	EnsureA(zone, name, address string, ttl time.Duration) error
+
+	// EnsureAAAA creates or updates an AAAA record so that name resolves to
+	// address, with the given TTL.
+	EnsureAAAA(zone, name, address string, ttl time.Duration) error
+
+	// DeleteRecord removes the record of the given type and name from the
+	// zone.
+	DeleteRecord(zone, name, rrType string) error
+
+	// ListRecords lists the records currently present in the zone.
+	ListRecords(zone string) ([]libdns.Record, error)
+}
@@ -0,0 +1,329 @@
+// Package dnscmd implements the dnscmd Windows DNS backend, for older
+// Windows Server targets that predate the DnsServer PowerShell module and
+// only expose dnscmd.exe. Commands are still issued over SSH, but using
+// dnscmd's record-add/record-delete syntax instead of PowerShell cmdlets.
+package dnscmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/libdns/libdns"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func init() {
+	caddy.RegisterModule(new(Backend))
+}
+
+// Backend drives a Windows DNS server over SSH using dnscmd.exe.
+type Backend struct {
+	Host     string `json:"host,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// KnownHostsFile is the path to an OpenSSH known_hosts file used to
+	// verify the DNS server's host key. Defaults to $HOME/.ssh/known_hosts.
+	KnownHostsFile string `json:"known_hosts,omitempty"`
+
+	// HostKey, if set, pins the DNS server's host key as a base64-encoded
+	// public key, instead of checking KnownHostsFile.
+	HostKey string `json:"host_key,omitempty"`
+
+	hostKeyCallback ssh.HostKeyCallback
+
+	// dnscmd has no TTL syntax at all, so ListRecords can't report the real
+	// TTL of a record it lists. lastTTLs remembers the TTL each Ensure* call
+	// was asked to apply, keyed by "zone/rrType/name", so ListRecords can
+	// echo it back instead of always reporting zero - otherwise the generic
+	// reconcile loop in dynamicdns.go would see permanent TTL drift and
+	// re-issue an Ensure* call for every record on every pass.
+	ttlMu    sync.Mutex
+	lastTTLs map[string]time.Duration
+}
+
+func (Backend) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "dynamic_dns.backends.dnscmd",
+		New: func() caddy.Module { return new(Backend) },
+	}
+}
+
+// Provision sets up the backend.
+func (b *Backend) Provision(_ caddy.Context) error {
+	if b.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+
+	callback, err := b.buildHostKeyCallback()
+	if err != nil {
+		return err
+	}
+	b.hostKeyCallback = callback
+
+	return nil
+}
+
+// buildHostKeyCallback returns a callback that verifies the DNS server's
+// host key against HostKey, if pinned, or else against KnownHostsFile
+// (defaulting to $HOME/.ssh/known_hosts). Provisioning fails rather than
+// silently accepting any host key if neither is usable.
+func (b *Backend) buildHostKeyCallback() (ssh.HostKeyCallback, error) {
+	if b.HostKey != "" {
+		keyBytes, err := base64.StdEncoding.DecodeString(b.HostKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host_key: %v", err)
+		}
+		pub, err := ssh.ParsePublicKey(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host_key: %v", err)
+		}
+		return ssh.FixedHostKey(pub), nil
+	}
+
+	knownHostsFile := b.KnownHostsFile
+	if knownHostsFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("determining default known_hosts path: %v", err)
+		}
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	if _, err := os.Stat(knownHostsFile); err != nil {
+		return nil, fmt.Errorf("known_hosts file %q not found and no host_key pinned: %v", knownHostsFile, err)
+	}
+
+	callback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts file %q: %v", knownHostsFile, err)
+	}
+	return callback, nil
+}
+
+// UnmarshalCaddyfile sets up the backend from Caddyfile tokens. Syntax:
+//
+//	dnscmd {
+//	    host        <host>
+//	    user        <user>
+//	    password    <password>
+//	    known_hosts <path>
+//	    host_key    <base64>
+//	}
+func (b *Backend) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	for d.Next() {
+		for nesting := d.Nesting(); d.NextBlock(nesting); {
+			switch d.Val() {
+			case "host":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.Host = d.Val()
+			case "user":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.User = d.Val()
+			case "password":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.Password = d.Val()
+			case "known_hosts":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.KnownHostsFile = d.Val()
+			case "host_key":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.HostKey = d.Val()
+			default:
+				return d.Errf("unrecognized subdirective '%s'", d.Val())
+			}
+		}
+	}
+	return nil
+}
+
+// EnsureCNAME creates or updates a CNAME record. dnscmd has no update verb,
+// so an existing record is deleted before the replacement is added.
+func (b *Backend) EnsureCNAME(zone, name, target string, ttl time.Duration) error {
+	b.rememberTTL(zone, "CNAME", name, ttl)
+	return b.ensure(zone, "CNAME", "CNAME", name, target)
+}
+
+// EnsureTXT creates or updates a TXT record.
+func (b *Backend) EnsureTXT(zone, name, value string, ttl time.Duration) error {
+	b.rememberTTL(zone, "TXT", name, ttl)
+	return b.ensure(zone, "ATXT", "TXT", name, value)
+}
+
+// EnsureA creates or updates an A record.
+func (b *Backend) EnsureA(zone, name, address string, ttl time.Duration) error {
+	b.rememberTTL(zone, "A", name, ttl)
+	return b.ensure(zone, "A", "A", name, address)
+}
+
+// EnsureAAAA creates or updates an AAAA record.
+func (b *Backend) EnsureAAAA(zone, name, address string, ttl time.Duration) error {
+	b.rememberTTL(zone, "AAAA", name, ttl)
+	return b.ensure(zone, "AAAA", "AAAA", name, address)
+}
+
+// rememberTTL records the TTL an Ensure* call was asked to apply to
+// zone/rrType/name, so ListRecords can echo it back since dnscmd itself has
+// no way to report it.
+func (b *Backend) rememberTTL(zone, rrType, name string, ttl time.Duration) {
+	b.ttlMu.Lock()
+	defer b.ttlMu.Unlock()
+	if b.lastTTLs == nil {
+		b.lastTTLs = make(map[string]time.Duration)
+	}
+	b.lastTTLs[zone+"/"+strings.ToUpper(rrType)+"/"+name] = ttl
+}
+
+// rememberedTTL returns the TTL last remembered for zone/rrType/name, if
+// any.
+func (b *Backend) rememberedTTL(zone, rrType, name string) (time.Duration, bool) {
+	b.ttlMu.Lock()
+	defer b.ttlMu.Unlock()
+	ttl, ok := b.lastTTLs[zone+"/"+strings.ToUpper(rrType)+"/"+name]
+	return ttl, ok
+}
+
+// ensure creates or updates a record. dnscmdType is the verb dnscmd expects
+// after /RecordAdd or /RecordDelete (e.g. "ATXT" for TXT records); rrType is
+// the plain record type as reported by /EnumRecords.
+func (b *Backend) ensure(zone, dnscmdType, rrType, name, data string) error {
+	existing, err := b.ListRecords(zone)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range existing {
+		if strings.EqualFold(rec.Type, rrType) && strings.EqualFold(rec.Name, name) {
+			if strings.EqualFold(rec.Value, data) {
+				return nil
+			}
+			if _, err := b.run(fmt.Sprintf("dnscmd /RecordDelete %s %s %s /f", zone, name, dnscmdType)); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	_, err = b.run(fmt.Sprintf("dnscmd /RecordAdd %s %s %s %s", zone, name, dnscmdType, data))
+	return err
+}
+
+// DeleteRecord removes the record of the given type and name from the zone.
+func (b *Backend) DeleteRecord(zone, name, rrType string) error {
+	dnscmdType := strings.ToUpper(rrType)
+	if dnscmdType == "TXT" {
+		dnscmdType = "ATXT"
+	}
+	_, err := b.run(fmt.Sprintf("dnscmd /RecordDelete %s %s %s /f", zone, name, dnscmdType))
+	if err == nil {
+		b.forgetTTL(zone, rrType, name)
+	}
+	return err
+}
+
+// forgetTTL discards any TTL remembered for zone/rrType/name, so a later
+// record of the same name created with a different TTL isn't reported with
+// the stale one.
+func (b *Backend) forgetTTL(zone, rrType, name string) {
+	b.ttlMu.Lock()
+	defer b.ttlMu.Unlock()
+	delete(b.lastTTLs, zone+"/"+strings.ToUpper(rrType)+"/"+name)
+}
+
+// ListRecords lists the records currently present in the zone. dnscmd's
+// output has no TTL column, so each record's TTL is filled in from the last
+// value an Ensure* call was asked to apply, if we have one on record.
+func (b *Backend) ListRecords(zone string) ([]libdns.Record, error) {
+	output, err := b.run(fmt.Sprintf("dnscmd /EnumRecords %s @", zone))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %v", err)
+	}
+
+	recs := parseDnscmdOutput(output)
+	for i := range recs {
+		if ttl, ok := b.rememberedTTL(zone, recs[i].Type, recs[i].Name); ok {
+			recs[i].TTL = ttl
+		}
+	}
+	return recs, nil
+}
+
+// run opens an SSH session to the DNS server and executes cmd, returning
+// its combined output.
+func (b *Backend) run(cmd string) (string, error) {
+	config := &ssh.ClientConfig{
+		User: b.User,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(b.Password),
+		},
+		HostKeyCallback: b.hostKeyCallback,
+	}
+
+	client, err := ssh.Dial("tcp", b.Host+":22", config)
+	if err != nil {
+		return "", fmt.Errorf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %v", err)
+	}
+	defer session.Close()
+
+	output, err := session.CombinedOutput(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to run command: %v, output: %s", err, string(output))
+	}
+
+	return string(output), nil
+}
+
+// parseDnscmdOutput is deliberately conservative: dnscmd's text output
+// format varies across Windows Server versions, so unrecognized lines are
+// skipped rather than erroring.
+func parseDnscmdOutput(output string) []libdns.Record {
+	var recs []libdns.Record
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		rrType := strings.ToUpper(fields[1])
+		switch rrType {
+		case "CNAME", "TXT", "A", "AAAA":
+		default:
+			continue
+		}
+		recs = append(recs, libdns.Record{
+			Name:  fields[0],
+			Type:  rrType,
+			Value: strings.Trim(fields[len(fields)-1], `"`),
+		})
+	}
+	return recs
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner     = (*Backend)(nil)
+	_ caddyfile.Unmarshaler = (*Backend)(nil)
+)
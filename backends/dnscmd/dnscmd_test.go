@@ -0,0 +1,69 @@
+package dnscmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestParseDnscmdOutput(t *testing.T) {
+	output := "" +
+		"www                 CNAME                  example.com.\n" +
+		"Returned records: 1\n" +
+		"@                   A                      1.2.3.4\n" +
+		"txt                 TXT                    \"hello\"\n" +
+		"weird line that isn't a record\n"
+
+	recs := parseDnscmdOutput(output)
+
+	want := []libdns.Record{
+		{Name: "www", Type: "CNAME", Value: "example.com."},
+		{Name: "@", Type: "A", Value: "1.2.3.4"},
+		{Name: "txt", Type: "TXT", Value: "hello"},
+	}
+	if len(recs) != len(want) {
+		t.Fatalf("got %d records, want %d: %+v", len(recs), len(want), recs)
+	}
+	for i := range want {
+		if recs[i] != want[i] {
+			t.Errorf("record %d: got %+v, want %+v", i, recs[i], want[i])
+		}
+	}
+}
+
+func TestParseDnscmdOutputSkipsUnsupportedTypes(t *testing.T) {
+	recs := parseDnscmdOutput("www                 SRV                    0 0 443 target.example.com.\n")
+	if len(recs) != 0 {
+		t.Errorf("expected unsupported record type to be skipped, got %+v", recs)
+	}
+}
+
+func TestRememberAndEchoTTL(t *testing.T) {
+	var b Backend
+
+	b.rememberTTL("example.com", "cname", "www", 5*time.Minute)
+
+	ttl, ok := b.rememberedTTL("example.com", "CNAME", "www")
+	if !ok {
+		t.Fatal("expected a remembered TTL")
+	}
+	if ttl != 5*time.Minute {
+		t.Errorf("got %v, want %v", ttl, 5*time.Minute)
+	}
+
+	if _, ok := b.rememberedTTL("example.com", "CNAME", "other"); ok {
+		t.Error("expected no remembered TTL for a different name")
+	}
+}
+
+func TestForgetTTL(t *testing.T) {
+	var b Backend
+	b.rememberTTL("example.com", "A", "www", time.Hour)
+
+	b.forgetTTL("example.com", "a", "www")
+
+	if _, ok := b.rememberedTTL("example.com", "A", "www"); ok {
+		t.Error("expected TTL to be forgotten")
+	}
+}
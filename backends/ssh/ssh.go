@@ -0,0 +1,379 @@
+// Package ssh implements the ssh Windows DNS backend: it dials the DNS
+// server over SSH and drives PowerShell's DnsServer cmdlets, the same way
+// this module has always worked.
+package ssh
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/libdns/libdns"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func init() {
+	caddy.RegisterModule(new(Backend))
+}
+
+// Backend drives a Windows DNS server over SSH with PowerShell.
+type Backend struct {
+	Host     string `json:"host,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// KnownHostsFile is the path to an OpenSSH known_hosts file used to
+	// verify the DNS server's host key. Defaults to $HOME/.ssh/known_hosts.
+	KnownHostsFile string `json:"known_hosts,omitempty"`
+
+	// HostKey, if set, pins the DNS server's host key as a base64-encoded
+	// public key, instead of checking KnownHostsFile.
+	HostKey string `json:"host_key,omitempty"`
+
+	hostKeyCallback ssh.HostKeyCallback
+}
+
+func (Backend) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "dynamic_dns.backends.ssh",
+		New: func() caddy.Module { return new(Backend) },
+	}
+}
+
+// Provision sets up the backend.
+func (b *Backend) Provision(_ caddy.Context) error {
+	if b.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+
+	callback, err := b.buildHostKeyCallback()
+	if err != nil {
+		return err
+	}
+	b.hostKeyCallback = callback
+
+	return nil
+}
+
+// buildHostKeyCallback returns a callback that verifies the DNS server's
+// host key against HostKey, if pinned, or else against KnownHostsFile
+// (defaulting to $HOME/.ssh/known_hosts). Provisioning fails rather than
+// silently accepting any host key if neither is usable.
+func (b *Backend) buildHostKeyCallback() (ssh.HostKeyCallback, error) {
+	if b.HostKey != "" {
+		keyBytes, err := base64.StdEncoding.DecodeString(b.HostKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host_key: %v", err)
+		}
+		pub, err := ssh.ParsePublicKey(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host_key: %v", err)
+		}
+		return ssh.FixedHostKey(pub), nil
+	}
+
+	knownHostsFile := b.KnownHostsFile
+	if knownHostsFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("determining default known_hosts path: %v", err)
+		}
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	if _, err := os.Stat(knownHostsFile); err != nil {
+		return nil, fmt.Errorf("known_hosts file %q not found and no host_key pinned: %v", knownHostsFile, err)
+	}
+
+	callback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts file %q: %v", knownHostsFile, err)
+	}
+	return callback, nil
+}
+
+// UnmarshalCaddyfile sets up the backend from Caddyfile tokens. Syntax:
+//
+//	ssh {
+//	    host        <host>
+//	    user        <user>
+//	    password    <password>
+//	    known_hosts <path>
+//	    host_key    <base64>
+//	}
+func (b *Backend) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	for d.Next() {
+		for nesting := d.Nesting(); d.NextBlock(nesting); {
+			switch d.Val() {
+			case "host":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.Host = d.Val()
+			case "user":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.User = d.Val()
+			case "password":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.Password = d.Val()
+			case "known_hosts":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.KnownHostsFile = d.Val()
+			case "host_key":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.HostKey = d.Val()
+			default:
+				return d.Errf("unrecognized subdirective '%s'", d.Val())
+			}
+		}
+	}
+	return nil
+}
+
+// EnsureCNAME creates or updates a CNAME record.
+func (b *Backend) EnsureCNAME(zone, name, target string, ttl time.Duration) error {
+	return b.ensure(zone, libdns.Record{Type: "CNAME", Name: name, Value: target, TTL: ttl})
+}
+
+// EnsureTXT creates or updates a TXT record.
+func (b *Backend) EnsureTXT(zone, name, value string, ttl time.Duration) error {
+	return b.ensure(zone, libdns.Record{Type: "TXT", Name: name, Value: value, TTL: ttl})
+}
+
+// EnsureA creates or updates an A record.
+func (b *Backend) EnsureA(zone, name, address string, ttl time.Duration) error {
+	return b.ensure(zone, libdns.Record{Type: "A", Name: name, Value: address, TTL: ttl})
+}
+
+// EnsureAAAA creates or updates an AAAA record.
+func (b *Backend) EnsureAAAA(zone, name, address string, ttl time.Duration) error {
+	return b.ensure(zone, libdns.Record{Type: "AAAA", Name: name, Value: address, TTL: ttl})
+}
+
+func (b *Backend) ensure(zone string, rec libdns.Record) error {
+	existing, err := b.ListRecords(zone)
+	if err != nil {
+		return err
+	}
+
+	if found := findRecord(existing, rec); found != nil {
+		if recordsEqual(*found, rec) {
+			return nil
+		}
+		return b.setRecord(zone, rec)
+	}
+	return b.addRecord(zone, rec)
+}
+
+// DeleteRecord removes the record of the given type and name from the zone.
+func (b *Backend) DeleteRecord(zone, name, rrType string) error {
+	rrType, field, err := rrTypeAndField(rrType)
+	if err != nil {
+		return err
+	}
+	_ = field
+	_, err = b.run(fmt.Sprintf("Remove-DnsServerResourceRecord -ZoneName %s -Name %s -RRType %s -Force", zone, name, rrType))
+	return err
+}
+
+// ListRecords lists the records currently present in the zone.
+func (b *Backend) ListRecords(zone string) ([]libdns.Record, error) {
+	output, err := b.run(fmt.Sprintf("Get-DnsServerResourceRecord -ZoneName %s | ConvertTo-Json", zone))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %v", err)
+	}
+	return parseResourceRecords(output)
+}
+
+func (b *Backend) addRecord(zone string, rec libdns.Record) error {
+	ttl := int(ttlOrDefault(rec.TTL).Seconds())
+	switch strings.ToUpper(rec.Type) {
+	case "CNAME":
+		_, err := b.run(fmt.Sprintf("Add-DnsServerResourceRecordCName -ZoneName %s -Name %s -HostNameAlias %s -TimeToLive ([TimeSpan]::FromSeconds(%d))", zone, rec.Name, rec.Value, ttl))
+		return err
+	case "TXT":
+		_, err := b.run(fmt.Sprintf("Add-DnsServerResourceRecord -ZoneName %s -Txt -Name %s -DescriptiveText %q -TimeToLive ([TimeSpan]::FromSeconds(%d))", zone, rec.Name, rec.Value, ttl))
+		return err
+	case "A":
+		_, err := b.run(fmt.Sprintf("Add-DnsServerResourceRecordA -ZoneName %s -Name %s -IPv4Address %s -TimeToLive ([TimeSpan]::FromSeconds(%d))", zone, rec.Name, rec.Value, ttl))
+		return err
+	case "AAAA":
+		_, err := b.run(fmt.Sprintf("Add-DnsServerResourceRecordAAAA -ZoneName %s -Name %s -IPv6Address %s -TimeToLive ([TimeSpan]::FromSeconds(%d))", zone, rec.Name, rec.Value, ttl))
+		return err
+	default:
+		return fmt.Errorf("unsupported record type: %s", rec.Type)
+	}
+}
+
+func (b *Backend) setRecord(zone string, rec libdns.Record) error {
+	rrType, field, err := rrTypeAndField(rec.Type)
+	if err != nil {
+		return err
+	}
+
+	cmd := fmt.Sprintf(
+		"$old = Get-DnsServerResourceRecord -ZoneName %s -Name %s -RRType %s; "+
+			"$new = $old.Clone(); $new.RecordData.%s = %q; $new.TimeToLive = [TimeSpan]::FromSeconds(%d); "+
+			"Set-DnsServerResourceRecord -ZoneName %s -OldInputObject $old -NewInputObject $new",
+		zone, rec.Name, rrType, field, rec.Value, int(ttlOrDefault(rec.TTL).Seconds()), zone,
+	)
+	_, err = b.run(cmd)
+	return err
+}
+
+// run opens an SSH session to the DNS server and executes cmd as a
+// PowerShell command, returning its combined output.
+func (b *Backend) run(cmd string) (string, error) {
+	config := &ssh.ClientConfig{
+		User: b.User,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(b.Password),
+		},
+		HostKeyCallback: b.hostKeyCallback,
+	}
+
+	client, err := ssh.Dial("tcp", b.Host+":22", config)
+	if err != nil {
+		return "", fmt.Errorf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %v", err)
+	}
+	defer session.Close()
+
+	fullCmd := fmt.Sprintf("powershell -Command \"%s\"", cmd)
+
+	output, err := session.CombinedOutput(fullCmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to run command: %v, output: %s", err, string(output))
+	}
+
+	if strings.Contains(string(output), "Error") {
+		return "", fmt.Errorf("DNS record update failed: %s", string(output))
+	}
+
+	return string(output), nil
+}
+
+func rrTypeAndField(recordType string) (rrType, field string, err error) {
+	switch strings.ToUpper(recordType) {
+	case "CNAME":
+		return "CName", "HostNameAlias", nil
+	case "TXT":
+		return "Txt", "DescriptiveText", nil
+	case "A":
+		return "A", "IPv4Address", nil
+	case "AAAA":
+		return "AAAA", "IPv6Address", nil
+	default:
+		return "", "", fmt.Errorf("unsupported record type: %s", recordType)
+	}
+}
+
+func ttlOrDefault(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+func recordsEqual(a, b libdns.Record) bool {
+	return strings.EqualFold(a.Value, b.Value) && ttlOrDefault(a.TTL) == ttlOrDefault(b.TTL)
+}
+
+func findRecord(recs []libdns.Record, rec libdns.Record) *libdns.Record {
+	for i := range recs {
+		if strings.EqualFold(recs[i].Type, rec.Type) && strings.EqualFold(recs[i].Name, rec.Name) {
+			return &recs[i]
+		}
+	}
+	return nil
+}
+
+// dnsServerResourceRecord mirrors the shape of the objects emitted by
+// PowerShell's `Get-DnsServerResourceRecord | ConvertTo-Json`.
+type dnsServerResourceRecord struct {
+	HostName   string `json:"HostName"`
+	RecordType string `json:"RecordType"`
+	TimeToLive struct {
+		TotalSeconds float64 `json:"TotalSeconds"`
+	} `json:"TimeToLive"`
+	RecordData struct {
+		HostNameAlias   string `json:"HostNameAlias"`
+		DescriptiveText string `json:"DescriptiveText"`
+		IPv4Address     string `json:"IPv4Address"`
+		IPv6Address     string `json:"IPv6Address"`
+	} `json:"RecordData"`
+}
+
+// parseResourceRecords turns the JSON emitted by
+// `Get-DnsServerResourceRecord | ConvertTo-Json` into libdns.Records.
+// ConvertTo-Json emits a single object, rather than an array, when only one
+// record is returned, so both shapes are handled here.
+func parseResourceRecords(output string) ([]libdns.Record, error) {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil, nil
+	}
+
+	var raw []dnsServerResourceRecord
+	if strings.HasPrefix(output, "[") {
+		if err := json.Unmarshal([]byte(output), &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse records: %v", err)
+		}
+	} else {
+		var single dnsServerResourceRecord
+		if err := json.Unmarshal([]byte(output), &single); err != nil {
+			return nil, fmt.Errorf("failed to parse records: %v", err)
+		}
+		raw = []dnsServerResourceRecord{single}
+	}
+
+	recs := make([]libdns.Record, 0, len(raw))
+	for _, r := range raw {
+		rec := libdns.Record{
+			Type: strings.ToUpper(r.RecordType),
+			Name: r.HostName,
+			TTL:  time.Duration(r.TimeToLive.TotalSeconds) * time.Second,
+		}
+		switch rec.Type {
+		case "CNAME":
+			rec.Value = r.RecordData.HostNameAlias
+		case "TXT":
+			rec.Value = r.RecordData.DescriptiveText
+		case "A":
+			rec.Value = r.RecordData.IPv4Address
+		case "AAAA":
+			rec.Value = r.RecordData.IPv6Address
+		default:
+			continue
+		}
+		recs = append(recs, rec)
+	}
+
+	return recs, nil
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner     = (*Backend)(nil)
+	_ caddyfile.Unmarshaler = (*Backend)(nil)
+)
@@ -0,0 +1,125 @@
+package ssh
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestParseResourceRecordsSingleObject(t *testing.T) {
+	output := `{
+		"HostName": "www",
+		"RecordType": "CNAME",
+		"TimeToLive": {"TotalSeconds": 3600},
+		"RecordData": {"HostNameAlias": "example.com."}
+	}`
+
+	recs, err := parseResourceRecords(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recs) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(recs))
+	}
+	want := libdns.Record{Name: "www", Type: "CNAME", Value: "example.com.", TTL: time.Hour}
+	if recs[0] != want {
+		t.Errorf("got %+v, want %+v", recs[0], want)
+	}
+}
+
+func TestParseResourceRecordsArray(t *testing.T) {
+	output := `[
+		{"HostName": "www", "RecordType": "cname", "TimeToLive": {"TotalSeconds": 60}, "RecordData": {"HostNameAlias": "example.com."}},
+		{"HostName": "@", "RecordType": "A", "TimeToLive": {"TotalSeconds": 300}, "RecordData": {"IPv4Address": "1.2.3.4"}}
+	]`
+
+	recs, err := parseResourceRecords(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(recs))
+	}
+	if recs[0].Type != "CNAME" || recs[0].Value != "example.com." {
+		t.Errorf("got %+v", recs[0])
+	}
+	if recs[1].Type != "A" || recs[1].Value != "1.2.3.4" {
+		t.Errorf("got %+v", recs[1])
+	}
+}
+
+func TestParseResourceRecordsEmpty(t *testing.T) {
+	recs, err := parseResourceRecords("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recs != nil {
+		t.Errorf("expected nil records, got %+v", recs)
+	}
+}
+
+func TestParseResourceRecordsUnknownTypeSkipped(t *testing.T) {
+	output := `{"HostName": "www", "RecordType": "SRV", "TimeToLive": {"TotalSeconds": 60}, "RecordData": {}}`
+
+	recs, err := parseResourceRecords(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recs) != 0 {
+		t.Errorf("expected unsupported record type to be skipped, got %+v", recs)
+	}
+}
+
+func TestRrTypeAndField(t *testing.T) {
+	cases := []struct {
+		recordType, rrType, field string
+	}{
+		{"CNAME", "CName", "HostNameAlias"},
+		{"cname", "CName", "HostNameAlias"},
+		{"TXT", "Txt", "DescriptiveText"},
+		{"A", "A", "IPv4Address"},
+		{"AAAA", "AAAA", "IPv6Address"},
+	}
+	for _, c := range cases {
+		rrType, field, err := rrTypeAndField(c.recordType)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.recordType, err)
+			continue
+		}
+		if rrType != c.rrType || field != c.field {
+			t.Errorf("%s: got (%s, %s), want (%s, %s)", c.recordType, rrType, field, c.rrType, c.field)
+		}
+	}
+
+	if _, _, err := rrTypeAndField("MX"); err == nil {
+		t.Error("expected error for unsupported record type")
+	}
+}
+
+func TestRecordsEqual(t *testing.T) {
+	a := libdns.Record{Value: "Example.com.", TTL: 0}
+	b := libdns.Record{Value: "example.com.", TTL: time.Hour}
+	if !recordsEqual(a, b) {
+		t.Error("expected records to compare equal: case-insensitive value match and zero TTL normalized to the 1h default")
+	}
+
+	c := libdns.Record{Value: "example.com.", TTL: 2 * time.Hour}
+	if recordsEqual(b, c) {
+		t.Error("expected records with different TTLs to compare unequal")
+	}
+}
+
+func TestFindRecord(t *testing.T) {
+	recs := []libdns.Record{
+		{Type: "CNAME", Name: "www", Value: "example.com."},
+		{Type: "A", Name: "@", Value: "1.2.3.4"},
+	}
+
+	if found := findRecord(recs, libdns.Record{Type: "cname", Name: "WWW"}); found == nil {
+		t.Error("expected case-insensitive match to find the CNAME record")
+	}
+	if found := findRecord(recs, libdns.Record{Type: "AAAA", Name: "@"}); found != nil {
+		t.Errorf("expected no match for AAAA, got %+v", found)
+	}
+}
@@ -0,0 +1,306 @@
+// Package winrm implements the winrm Windows DNS backend: it drives the
+// same PowerShell DnsServer cmdlets as the ssh backend, but over
+// WS-Management instead of SSH, for Windows shops that don't expose
+// OpenSSH on their DNS servers.
+package winrm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/libdns/libdns"
+	"github.com/masterzen/winrm"
+)
+
+func init() {
+	caddy.RegisterModule(new(Backend))
+}
+
+// Backend drives a Windows DNS server over WinRM.
+type Backend struct {
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	UseTLS   bool   `json:"use_tls,omitempty"`
+
+	client *winrm.Client
+}
+
+func (Backend) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "dynamic_dns.backends.winrm",
+		New: func() caddy.Module { return new(Backend) },
+	}
+}
+
+// Provision sets up the backend.
+func (b *Backend) Provision(_ caddy.Context) error {
+	if b.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if b.Port == 0 {
+		b.Port = 5985
+		if b.UseTLS {
+			b.Port = 5986
+		}
+	}
+
+	endpoint := winrm.NewEndpoint(b.Host, b.Port, b.UseTLS, true, nil, nil, nil, 0)
+	client, err := winrm.NewClient(endpoint, b.User, b.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create winrm client: %v", err)
+	}
+	b.client = client
+
+	return nil
+}
+
+// UnmarshalCaddyfile sets up the backend from Caddyfile tokens. Syntax:
+//
+//	winrm {
+//	    host     <host>
+//	    port     <port>
+//	    user     <user>
+//	    password <password>
+//	    use_tls
+//	}
+func (b *Backend) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	for d.Next() {
+		for nesting := d.Nesting(); d.NextBlock(nesting); {
+			switch d.Val() {
+			case "host":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.Host = d.Val()
+			case "port":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				port, err := strconv.Atoi(d.Val())
+				if err != nil {
+					return d.Errf("invalid port: %v", err)
+				}
+				b.Port = port
+			case "user":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.User = d.Val()
+			case "password":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				b.Password = d.Val()
+			case "use_tls":
+				b.UseTLS = true
+			default:
+				return d.Errf("unrecognized subdirective '%s'", d.Val())
+			}
+		}
+	}
+	return nil
+}
+
+// EnsureCNAME creates or updates a CNAME record.
+func (b *Backend) EnsureCNAME(zone, name, target string, ttl time.Duration) error {
+	return b.ensure(zone, libdns.Record{Type: "CNAME", Name: name, Value: target, TTL: ttl})
+}
+
+// EnsureTXT creates or updates a TXT record.
+func (b *Backend) EnsureTXT(zone, name, value string, ttl time.Duration) error {
+	return b.ensure(zone, libdns.Record{Type: "TXT", Name: name, Value: value, TTL: ttl})
+}
+
+// EnsureA creates or updates an A record.
+func (b *Backend) EnsureA(zone, name, address string, ttl time.Duration) error {
+	return b.ensure(zone, libdns.Record{Type: "A", Name: name, Value: address, TTL: ttl})
+}
+
+// EnsureAAAA creates or updates an AAAA record.
+func (b *Backend) EnsureAAAA(zone, name, address string, ttl time.Duration) error {
+	return b.ensure(zone, libdns.Record{Type: "AAAA", Name: name, Value: address, TTL: ttl})
+}
+
+func (b *Backend) ensure(zone string, rec libdns.Record) error {
+	existing, err := b.ListRecords(zone)
+	if err != nil {
+		return err
+	}
+
+	if found := findRecord(existing, rec); found != nil {
+		if recordsEqual(*found, rec) {
+			return nil
+		}
+		return b.run(setRecordCommand(zone, rec))
+	}
+	return b.run(addRecordCommand(zone, rec))
+}
+
+// DeleteRecord removes the record of the given type and name from the zone.
+func (b *Backend) DeleteRecord(zone, name, rrType string) error {
+	rr, _, err := rrTypeAndField(rrType)
+	if err != nil {
+		return err
+	}
+	return b.run(fmt.Sprintf("Remove-DnsServerResourceRecord -ZoneName %s -Name %s -RRType %s -Force", zone, name, rr))
+}
+
+// ListRecords lists the records currently present in the zone.
+func (b *Backend) ListRecords(zone string) ([]libdns.Record, error) {
+	output, err := b.runRaw(fmt.Sprintf("Get-DnsServerResourceRecord -ZoneName %s | ConvertTo-Json", zone))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %v", err)
+	}
+	return parseResourceRecords(output)
+}
+
+func (b *Backend) run(cmd string) error {
+	_, err := b.runRaw(cmd)
+	return err
+}
+
+func (b *Backend) runRaw(cmd string) (string, error) {
+	stdout, stderr, _, err := b.client.RunWithString(winrm.Powershell(cmd), "")
+	if err != nil {
+		return "", fmt.Errorf("failed to run command: %v, stderr: %s", err, stderr)
+	}
+	if strings.Contains(stderr, "Error") {
+		return "", fmt.Errorf("DNS record update failed: %s", stderr)
+	}
+	return stdout, nil
+}
+
+func addRecordCommand(zone string, rec libdns.Record) string {
+	ttl := int(ttlOrDefault(rec.TTL).Seconds())
+	switch strings.ToUpper(rec.Type) {
+	case "CNAME":
+		return fmt.Sprintf("Add-DnsServerResourceRecordCName -ZoneName %s -Name %s -HostNameAlias %s -TimeToLive ([TimeSpan]::FromSeconds(%d))", zone, rec.Name, rec.Value, ttl)
+	case "A":
+		return fmt.Sprintf("Add-DnsServerResourceRecordA -ZoneName %s -Name %s -IPv4Address %s -TimeToLive ([TimeSpan]::FromSeconds(%d))", zone, rec.Name, rec.Value, ttl)
+	case "AAAA":
+		return fmt.Sprintf("Add-DnsServerResourceRecordAAAA -ZoneName %s -Name %s -IPv6Address %s -TimeToLive ([TimeSpan]::FromSeconds(%d))", zone, rec.Name, rec.Value, ttl)
+	default:
+		return fmt.Sprintf("Add-DnsServerResourceRecord -ZoneName %s -Txt -Name %s -DescriptiveText %q -TimeToLive ([TimeSpan]::FromSeconds(%d))", zone, rec.Name, rec.Value, ttl)
+	}
+}
+
+func setRecordCommand(zone string, rec libdns.Record) string {
+	rrType, field, _ := rrTypeAndField(rec.Type)
+	return fmt.Sprintf(
+		"$old = Get-DnsServerResourceRecord -ZoneName %s -Name %s -RRType %s; "+
+			"$new = $old.Clone(); $new.RecordData.%s = %q; $new.TimeToLive = [TimeSpan]::FromSeconds(%d); "+
+			"Set-DnsServerResourceRecord -ZoneName %s -OldInputObject $old -NewInputObject $new",
+		zone, rec.Name, rrType, field, rec.Value, int(ttlOrDefault(rec.TTL).Seconds()), zone,
+	)
+}
+
+func rrTypeAndField(recordType string) (rrType, field string, err error) {
+	switch strings.ToUpper(recordType) {
+	case "CNAME":
+		return "CName", "HostNameAlias", nil
+	case "TXT":
+		return "Txt", "DescriptiveText", nil
+	case "A":
+		return "A", "IPv4Address", nil
+	case "AAAA":
+		return "AAAA", "IPv6Address", nil
+	default:
+		return "", "", fmt.Errorf("unsupported record type: %s", recordType)
+	}
+}
+
+func ttlOrDefault(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+func recordsEqual(a, b libdns.Record) bool {
+	return strings.EqualFold(a.Value, b.Value) && ttlOrDefault(a.TTL) == ttlOrDefault(b.TTL)
+}
+
+func findRecord(recs []libdns.Record, rec libdns.Record) *libdns.Record {
+	for i := range recs {
+		if strings.EqualFold(recs[i].Type, rec.Type) && strings.EqualFold(recs[i].Name, rec.Name) {
+			return &recs[i]
+		}
+	}
+	return nil
+}
+
+// dnsServerResourceRecord mirrors the shape of the objects emitted by
+// PowerShell's `Get-DnsServerResourceRecord | ConvertTo-Json`.
+type dnsServerResourceRecord struct {
+	HostName   string `json:"HostName"`
+	RecordType string `json:"RecordType"`
+	TimeToLive struct {
+		TotalSeconds float64 `json:"TotalSeconds"`
+	} `json:"TimeToLive"`
+	RecordData struct {
+		HostNameAlias   string `json:"HostNameAlias"`
+		DescriptiveText string `json:"DescriptiveText"`
+		IPv4Address     string `json:"IPv4Address"`
+		IPv6Address     string `json:"IPv6Address"`
+	} `json:"RecordData"`
+}
+
+// parseResourceRecords turns the JSON emitted by
+// `Get-DnsServerResourceRecord | ConvertTo-Json` into libdns.Records.
+// ConvertTo-Json emits a single object, rather than an array, when only one
+// record is returned, so both shapes are handled here.
+func parseResourceRecords(output string) ([]libdns.Record, error) {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil, nil
+	}
+
+	var raw []dnsServerResourceRecord
+	if strings.HasPrefix(output, "[") {
+		if err := json.Unmarshal([]byte(output), &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse records: %v", err)
+		}
+	} else {
+		var single dnsServerResourceRecord
+		if err := json.Unmarshal([]byte(output), &single); err != nil {
+			return nil, fmt.Errorf("failed to parse records: %v", err)
+		}
+		raw = []dnsServerResourceRecord{single}
+	}
+
+	recs := make([]libdns.Record, 0, len(raw))
+	for _, r := range raw {
+		rec := libdns.Record{
+			Name: r.HostName,
+			Type: strings.ToUpper(r.RecordType),
+			TTL:  time.Duration(r.TimeToLive.TotalSeconds) * time.Second,
+		}
+		switch rec.Type {
+		case "CNAME":
+			rec.Value = r.RecordData.HostNameAlias
+		case "TXT":
+			rec.Value = r.RecordData.DescriptiveText
+		case "A":
+			rec.Value = r.RecordData.IPv4Address
+		case "AAAA":
+			rec.Value = r.RecordData.IPv6Address
+		default:
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner     = (*Backend)(nil)
+	_ caddyfile.Unmarshaler = (*Backend)(nil)
+)
@@ -0,0 +1,118 @@
+package winrm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestParseResourceRecordsSingleObject(t *testing.T) {
+	output := `{
+		"HostName": "www",
+		"RecordType": "CNAME",
+		"TimeToLive": {"TotalSeconds": 3600},
+		"RecordData": {"HostNameAlias": "example.com."}
+	}`
+
+	recs, err := parseResourceRecords(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recs) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(recs))
+	}
+	want := libdns.Record{Name: "www", Type: "CNAME", Value: "example.com.", TTL: time.Hour}
+	if recs[0] != want {
+		t.Errorf("got %+v, want %+v", recs[0], want)
+	}
+}
+
+func TestParseResourceRecordsNormalizesCase(t *testing.T) {
+	// RecordType comes back from PowerShell in mixed case (e.g. "CName"),
+	// not the upper-case form the rest of this package compares against.
+	output := `{"HostName": "www", "RecordType": "CName", "TimeToLive": {"TotalSeconds": 60}, "RecordData": {"HostNameAlias": "example.com."}}`
+
+	recs, err := parseResourceRecords(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recs) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(recs))
+	}
+	if recs[0].Type != "CNAME" {
+		t.Errorf("expected normalized type CNAME, got %q", recs[0].Type)
+	}
+	if recs[0].Value != "example.com." {
+		t.Errorf("expected value to be populated once type matches the switch, got %q", recs[0].Value)
+	}
+}
+
+func TestParseResourceRecordsUnknownTypeSkipped(t *testing.T) {
+	output := `{"HostName": "www", "RecordType": "SRV", "TimeToLive": {"TotalSeconds": 60}, "RecordData": {}}`
+
+	recs, err := parseResourceRecords(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recs) != 0 {
+		t.Errorf("expected unsupported record type to be skipped, got %+v", recs)
+	}
+}
+
+func TestParseResourceRecordsArray(t *testing.T) {
+	output := `[
+		{"HostName": "www", "RecordType": "CNAME", "TimeToLive": {"TotalSeconds": 60}, "RecordData": {"HostNameAlias": "example.com."}},
+		{"HostName": "@", "RecordType": "AAAA", "TimeToLive": {"TotalSeconds": 300}, "RecordData": {"IPv6Address": "::1"}}
+	]`
+
+	recs, err := parseResourceRecords(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(recs))
+	}
+	if recs[1].Type != "AAAA" || recs[1].Value != "::1" {
+		t.Errorf("got %+v", recs[1])
+	}
+}
+
+func TestRecordsEqual(t *testing.T) {
+	a := libdns.Record{Value: "Example.com.", TTL: 0}
+	b := libdns.Record{Value: "example.com.", TTL: time.Hour}
+	if !recordsEqual(a, b) {
+		t.Error("expected case-insensitive value match with zero TTL normalized to the 1h default")
+	}
+
+	c := libdns.Record{Value: "example.com.", TTL: 2 * time.Hour}
+	if recordsEqual(b, c) {
+		t.Error("expected records with different TTLs to compare unequal")
+	}
+}
+
+func TestFindRecord(t *testing.T) {
+	recs := []libdns.Record{
+		{Type: "CNAME", Name: "www", Value: "example.com."},
+	}
+	if found := findRecord(recs, libdns.Record{Type: "cname", Name: "WWW"}); found == nil {
+		t.Error("expected case-insensitive match")
+	}
+	if found := findRecord(recs, libdns.Record{Type: "A", Name: "www"}); found != nil {
+		t.Errorf("expected no match for different type, got %+v", found)
+	}
+}
+
+func TestRrTypeAndField(t *testing.T) {
+	rrType, field, err := rrTypeAndField("cname")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rrType != "CName" || field != "HostNameAlias" {
+		t.Errorf("got (%s, %s)", rrType, field)
+	}
+
+	if _, _, err := rrTypeAndField("MX"); err == nil {
+		t.Error("expected error for unsupported record type")
+	}
+}
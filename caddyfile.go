@@ -1,10 +1,16 @@
 package dynamicdns
 
 import (
+	"strings"
+
 	"github.com/caddyserver/caddy/v2"
 	"github.com/caddyserver/caddy/v2/caddyconfig"
 	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
 	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+
+	_ "github.com/tony-butchart/caddy-windns-cnames/backends/dnscmd"
+	_ "github.com/tony-butchart/caddy-windns-cnames/backends/ssh"
+	_ "github.com/tony-butchart/caddy-windns-cnames/backends/winrm"
 )
 
 func init() {
@@ -17,18 +23,31 @@ func init() {
 //	dynamic_dns {
 //	    domains {
 //	        <zone> <names...>
+//	        <zone> {
+//	            cname <name> [-> <target>]
+//	            a     <name> [ip_source=<name>]
+//	            aaaa  <name> [ip_source=<name>]
+//	        }
 //	    }
+//	    ip_source <name> static <address>
+//	    ip_source <name> simple_http <url...>
+//	    ip_source <name> interface <iface>
+//	    ip_source <name> upnp
 //	    check_interval <duration>
 //	    dns_server {
-//	        host <host>
-//	        user <user>
-//	        password <password>
+//	        backend <name> {
+//	            ...
+//	        }
 //	    }
 //	    ttl <duration>
 //	    auto_cname [<zone>]
 //	}
 //
-// If <names...> are omitted after <zone>, then "@" will be assumed.
+// If <names...> are omitted after <zone>, then "@" will be assumed, and
+// each name becomes a CNAME record pointing at the zone apex. The nested
+// form supports A and AAAA records as well, sourced from a named ip_source.
+// An upnp ip_source is parsed, but provisioning the app rejects it, since
+// there is no UPnP lookup.
 func parseApp(d *caddyfile.Dispenser, _ interface{}) (interface{}, error) {
 	app := new(App)
 
@@ -41,20 +60,69 @@ func parseApp(d *caddyfile.Dispenser, _ interface{}) (interface{}, error) {
 	for d.NextBlock(0) {
 		switch d.Val() {
 		case "domains":
-			for nesting := d.Nesting(); d.NextBlock(nesting); {
+			zoneNesting := d.Nesting()
+			for d.NextBlock(zoneNesting) {
 				zone := d.Val()
 				if zone == "" {
 					return nil, d.ArgErr()
 				}
+
+				if app.Domains == nil {
+					app.Domains = make(map[string][]DomainRecord)
+				}
+
 				names := d.RemainingArgs()
+				if d.NextBlock(zoneNesting + 1) {
+					for {
+						rec, err := parseDomainRecord(d)
+						if err != nil {
+							return nil, err
+						}
+						app.Domains[zone] = append(app.Domains[zone], rec)
+						if !d.NextBlock(zoneNesting + 1) {
+							break
+						}
+					}
+					continue
+				}
+
 				if len(names) == 0 {
 					names = []string{"@"}
 				}
-				if app.Domains == nil {
-					app.Domains = make(map[string][]string)
+				for _, name := range names {
+					app.Domains[zone] = append(app.Domains[zone], DomainRecord{Name: name, Type: "CNAME"})
+				}
+			}
+		case "ip_source":
+			args := d.RemainingArgs()
+			if len(args) < 2 {
+				return nil, d.ArgErr()
+			}
+			name, cfg := args[0], IPSourceConfig{Type: args[1]}
+			switch args[1] {
+			case "static":
+				if len(args) < 3 {
+					return nil, d.Errf("static ip_source requires an address")
+				}
+				cfg.Address = args[2]
+			case "simple_http":
+				if len(args) < 3 {
+					return nil, d.Errf("simple_http ip_source requires at least one URL")
+				}
+				cfg.URLs = args[2:]
+			case "interface":
+				if len(args) < 3 {
+					return nil, d.Errf("interface ip_source requires an interface name")
 				}
-				app.Domains[zone] = append(app.Domains[zone], names...)
+				cfg.Interface = args[2]
+			case "upnp":
+			default:
+				return nil, d.Errf("unknown ip_source type '%s'", args[1])
 			}
+			if app.IPSources == nil {
+				app.IPSources = make(map[string]IPSourceConfig)
+			}
+			app.IPSources[name] = cfg
 		case "check_interval":
 			if !d.NextArg() {
 				return nil, d.ArgErr()
@@ -67,21 +135,16 @@ func parseApp(d *caddyfile.Dispenser, _ interface{}) (interface{}, error) {
 		case "dns_server":
 			for nesting := d.Nesting(); d.NextBlock(nesting); {
 				switch d.Val() {
-				case "host":
+				case "backend":
 					if !d.NextArg() {
 						return nil, d.ArgErr()
 					}
-					app.DNSServer.Host = d.Val()
-				case "user":
-					if !d.NextArg() {
-						return nil, d.ArgErr()
+					name := d.Val()
+					unm, err := caddyfile.UnmarshalModule(d, "dynamic_dns.backends."+name)
+					if err != nil {
+						return nil, err
 					}
-					app.DNSServer.User = d.Val()
-				case "password":
-					if !d.NextArg() {
-						return nil, d.ArgErr()
-					}
-					app.DNSServer.Password = d.Val()
+					app.DNSServer.BackendRaw = caddyconfig.JSONModuleObject(unm, "backend", name, nil)
 				default:
 					return nil, d.Errf("unknown dns_server property '%s'", d.Val())
 				}
@@ -109,3 +172,40 @@ func parseApp(d *caddyfile.Dispenser, _ interface{}) (interface{}, error) {
 		Value: caddyconfig.JSON(app, nil),
 	}, nil
 }
+
+// parseDomainRecord parses one record line from the nested per-zone
+// `domains` syntax. d must be positioned at the record type token (e.g.
+// "cname"). Syntax:
+//
+//	cname <name> [-> <target>]
+//	a     <name> [ip_source=<name>]
+//	aaaa  <name> [ip_source=<name>]
+func parseDomainRecord(d *caddyfile.Dispenser) (DomainRecord, error) {
+	recType := d.Val()
+	args := d.RemainingArgs()
+	if len(args) == 0 {
+		return DomainRecord{}, d.ArgErr()
+	}
+
+	rec := DomainRecord{Name: args[0], Type: strings.ToUpper(recType)}
+
+	switch rec.Type {
+	case "CNAME":
+		switch {
+		case len(args) >= 3 && args[1] == "->":
+			rec.Target = args[2]
+		case len(args) == 2:
+			rec.Target = args[1]
+		}
+	case "A", "AAAA":
+		for _, arg := range args[1:] {
+			if strings.HasPrefix(arg, "ip_source=") {
+				rec.IPSource = strings.TrimPrefix(arg, "ip_source=")
+			}
+		}
+	default:
+		return DomainRecord{}, d.Errf("unknown record type '%s'", recType)
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,78 @@
+package dynamicdns
+
+import (
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+func TestParseDomainRecordCNAME(t *testing.T) {
+	cases := []struct {
+		name       string
+		line       string
+		wantTarget string
+	}{
+		{"bare name defaults target", "cname www", ""},
+		{"two-arg form", "cname www example.com", "example.com"},
+		{"arrow form", "cname www -> example.com", "example.com"},
+	}
+
+	for _, c := range cases {
+		d := caddyfile.NewTestDispenser("dummy {\n" + c.line + "\n}")
+		d.Next() // consume "dummy"
+		d.Next() // consume "{"
+		d.Next() // position at the record type token
+
+		rec, err := parseDomainRecord(d)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if rec.Name != "www" {
+			t.Errorf("%s: got name %q, want %q", c.name, rec.Name, "www")
+		}
+		if rec.Type != "CNAME" {
+			t.Errorf("%s: got type %q, want CNAME", c.name, rec.Type)
+		}
+		if rec.Target != c.wantTarget {
+			t.Errorf("%s: got target %q, want %q", c.name, rec.Target, c.wantTarget)
+		}
+	}
+}
+
+func TestParseDomainRecordAddress(t *testing.T) {
+	d := caddyfile.NewTestDispenser("dummy {\na home ip_source=wan\n}")
+	d.Next()
+	d.Next()
+	d.Next()
+
+	rec, err := parseDomainRecord(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Type != "A" || rec.Name != "home" || rec.IPSource != "wan" {
+		t.Errorf("got %+v", rec)
+	}
+}
+
+func TestParseDomainRecordUnknownType(t *testing.T) {
+	d := caddyfile.NewTestDispenser("dummy {\nmx www\n}")
+	d.Next()
+	d.Next()
+	d.Next()
+
+	if _, err := parseDomainRecord(d); err == nil {
+		t.Error("expected error for unknown record type")
+	}
+}
+
+func TestParseDomainRecordMissingName(t *testing.T) {
+	d := caddyfile.NewTestDispenser("dummy {\ncname\n}")
+	d.Next()
+	d.Next()
+	d.Next()
+
+	if _, err := parseDomainRecord(d); err == nil {
+		t.Error("expected error when no name is given")
+	}
+}
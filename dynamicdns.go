@@ -15,15 +15,18 @@
 package dynamicdns
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyevents"
 	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/libdns/libdns"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/ssh"
 )
 
 func init() {
@@ -31,15 +34,22 @@ func init() {
 }
 
 type App struct {
-	// The configuration for the Windows DNS server
-	DNSServer struct {
-		Host     string `json:"host,omitempty"`
-		User     string `json:"user,omitempty"`
-		Password string `json:"password,omitempty"`
-	} `json:"dns_server,omitempty"`
-
-	// The record names, keyed by DNS zone, for which to update the CNAME records.
-	Domains map[string][]string `json:"domains,omitempty"`
+	// The configuration for the Windows DNS server. This is a Provider -
+	// the same type registered as the dns.providers.windns ACME DNS-01
+	// solver - so the `backend <name> {...}` subdirective here and the one
+	// under a `tls { dns windns {...} }` block are loaded and driven by the
+	// identical code path instead of App duplicating its own copy of it.
+	// Caddy still provisions each module instance separately, though, so
+	// the two never share a live backend instance, even when they're
+	// configured with the exact same block.
+	DNSServer Provider `json:"dns_server,omitempty"`
+
+	// The records to keep up to date, keyed by DNS zone.
+	Domains map[string][]DomainRecord `json:"domains,omitempty"`
+
+	// Named ways of discovering the address to use for an A or AAAA record,
+	// selected by a domains entry's ip_source.
+	IPSources map[string]IPSourceConfig `json:"ip_sources,omitempty"`
 
 	// How frequently to check and update DNS records. Default: 30m
 	CheckInterval caddy.Duration `json:"check_interval,omitempty"`
@@ -53,10 +63,46 @@ type App struct {
 	// The zone to use for automatic CNAME records
 	AutoCNAMEZone string `json:"auto_cname_zone,omitempty"`
 
+	// lastIPs remembers, per zone and record name, the address we last
+	// pushed for an A/AAAA record, so we don't re-issue an Ensure call every
+	// tick when the discovered address hasn't actually changed.
+	lastIPs map[string]map[string]string
+
+	// mu guards updateDNS and the state it reads and writes above, since it
+	// can be called concurrently from the check_interval ticker, the
+	// caddy.config_loaded event subscription, and the admin
+	// /dynamic_dns/reconcile endpoint (a fresh goroutine per request).
+	mu sync.Mutex
+
 	ctx    caddy.Context
 	logger *zap.Logger
 }
 
+// DomainRecord describes one DNS record this module should keep up to date.
+type DomainRecord struct {
+	// Name is the record name (e.g. "www"), or "@" for the zone apex.
+	Name string `json:"name"`
+
+	// Type is the record type: "CNAME" (the default), "A", or "AAAA".
+	Type string `json:"type,omitempty"`
+
+	// Target is the CNAME target, for Type "CNAME". If empty, the zone
+	// itself is used.
+	Target string `json:"target,omitempty"`
+
+	// IPSource names an entry in App.IPSources used to discover the address
+	// for Type "A" or "AAAA".
+	IPSource string `json:"ip_source,omitempty"`
+}
+
+// recordType returns Type, defaulting to "CNAME".
+func (r DomainRecord) recordType() string {
+	if r.Type == "" {
+		return "CNAME"
+	}
+	return strings.ToUpper(r.Type)
+}
+
 func (App) CaddyModule() caddy.ModuleInfo {
 	return caddy.ModuleInfo{
 		ID:  "dynamic_dns",
@@ -75,6 +121,42 @@ func (a *App) Provision(ctx caddy.Context) error {
 		return fmt.Errorf("check interval must be at least 1 second")
 	}
 
+	if err := a.DNSServer.Provision(ctx); err != nil {
+		return fmt.Errorf("provisioning dns_server: %v", err)
+	}
+
+	for name, cfg := range a.IPSources {
+		if _, err := cfg.build(); err != nil {
+			return fmt.Errorf("ip_source %q: %v", name, err)
+		}
+	}
+
+	if err := a.subscribeToConfigReloads(ctx); err != nil {
+		a.logger.Warn("could not subscribe to config reload events; DNS records will only be reconciled on check_interval",
+			zap.Error(err))
+	}
+
+	return nil
+}
+
+// subscribeToConfigReloads asks the events app to call updateDNS immediately
+// after every successful config reload, so a `caddy reload` that adds or
+// removes a route doesn't have to wait for the next check_interval tick.
+func (a *App) subscribeToConfigReloads(ctx caddy.Context) error {
+	eventsAppIface, err := ctx.App("events")
+	if err != nil {
+		return fmt.Errorf("events app not available: %v", err)
+	}
+	eventsApp, ok := eventsAppIface.(*caddyevents.App)
+	if !ok {
+		return fmt.Errorf("events app is not the expected type")
+	}
+
+	eventsApp.On("caddy.config_loaded", func(_ context.Context, _ caddyevents.Event) error {
+		a.updateDNS()
+		return nil
+	})
+
 	return nil
 }
 
@@ -111,66 +193,240 @@ func (a *App) checkerLoop() {
 }
 
 func (a *App) updateDNS() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	a.logger.Debug("beginning DNS update")
 
-	allDomains := a.allDomains()
-
-	for zone, domains := range allDomains {
-		for _, domain := range domains {
-			err := a.updateCNAME(zone, domain)
-			if err != nil {
-				a.logger.Error("failed updating CNAME record",
-					zap.String("zone", zone),
-					zap.String("domain", domain),
-					zap.Error(err))
-			} else {
-				a.logger.Info("updated CNAME record",
-					zap.String("zone", zone),
-					zap.String("domain", domain))
-			}
+	if a.lastIPs == nil {
+		a.lastIPs = make(map[string]map[string]string)
+	}
+
+	for zone, records := range a.allDomains() {
+		if err := a.reconcileZone(zone, records); err != nil {
+			a.logger.Error("failed reconciling zone",
+				zap.String("zone", zone),
+				zap.Error(err))
 		}
 	}
 
 	a.logger.Info("finished updating DNS")
 }
 
-func (a *App) updateCNAME(zone, domain string) error {
-	config := &ssh.ClientConfig{
-		User: a.DNSServer.User,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(a.DNSServer.Password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+// reconcileZone brings zone's records in line with records, adding missing
+// ones, updating ones whose target, address, or TTL has drifted, and
+// removing records that we previously created but are no longer desired.
+//
+// Caddy discards the App (and any in-process state it held) on every config
+// reload, so "no longer desired" can't be detected by comparing against what
+// a previous reconcile pass remembered - there's no guarantee a previous
+// pass ran in this process at all. Instead, every record this module creates
+// is tagged with a TXT ownership marker (see ensureMarker), and staleness is
+// determined by diffing the zone's actual records - the one piece of state
+// that does survive a reload - against the current desired set.
+func (a *App) reconcileZone(zone string, records []DomainRecord) error {
+	actual, err := a.listRecords(zone)
+	if err != nil {
+		return fmt.Errorf("failed to list existing records: %v", err)
+	}
+
+	desired := make(map[string]bool, len(records))
+	for _, rec := range records {
+		desired[recordKey(rec.recordType(), rec.Name)] = true
+
+		switch rec.recordType() {
+		case "CNAME":
+			a.reconcileCNAME(zone, rec, actual)
+		case "A":
+			a.reconcileAddress(zone, rec, actual, "A")
+		case "AAAA":
+			a.reconcileAddress(zone, rec, actual, "AAAA")
+		default:
+			a.logger.Error("unsupported record type",
+				zap.String("zone", zone), zap.String("domain", rec.Name), zap.String("type", rec.Type))
+			continue
+		}
+
+		a.ensureMarker(zone, rec.recordType(), rec.Name, actual)
+	}
+
+	a.removeStaleRecords(zone, desired, actual)
+
+	return nil
+}
+
+// dynamicDNSMarkerValue is the fixed TXT value ensureMarker writes, so
+// removeStaleRecords can recognize a TXT record as one of its own ownership
+// markers rather than an unrelated TXT record a zone happens to contain.
+const dynamicDNSMarkerValue = "managed-by-dynamic_dns"
+
+// ensureMarker creates the TXT ownership marker for zone/rrType/name, if it
+// doesn't already exist, so a later reconcile pass - possibly in a different
+// process, after a config reload - can tell that this record was created by
+// this module and is safe to remove once it drops out of the desired set.
+func (a *App) ensureMarker(zone, rrType, name string, actual map[string]libdns.Record) {
+	marker := markerName(rrType, name)
+	if have, ok := actual[recordKey("TXT", marker)]; ok && have.Value == dynamicDNSMarkerValue {
+		return
+	}
+	if err := a.DNSServer.backend.EnsureTXT(zone, marker, dynamicDNSMarkerValue, time.Duration(a.TTL)); err != nil {
+		a.logger.Error("failed creating ownership marker",
+			zap.String("zone", zone), zap.String("domain", name), zap.String("type", rrType), zap.Error(err))
+	}
+}
+
+// removeStaleRecords deletes any record whose ownership marker is present in
+// actual but whose type/name no longer appears in desired: it was declared
+// in an earlier config and has since been removed.
+func (a *App) removeStaleRecords(zone string, desired map[string]bool, actual map[string]libdns.Record) {
+	for key, rec := range actual {
+		rrType, name := splitRecordKey(key)
+		if rrType != "TXT" || rec.Value != dynamicDNSMarkerValue {
+			continue
+		}
+
+		managedType, managedName, ok := parseMarkerName(name)
+		if !ok || desired[recordKey(managedType, managedName)] {
+			continue
+		}
+
+		if err := a.DNSServer.backend.DeleteRecord(zone, managedName, managedType); err != nil {
+			a.logger.Error("failed deleting stale record",
+				zap.String("zone", zone), zap.String("domain", managedName), zap.String("type", managedType), zap.Error(err))
+			continue
+		}
+		if err := a.DNSServer.backend.DeleteRecord(zone, name, "TXT"); err != nil {
+			a.logger.Error("failed deleting stale ownership marker",
+				zap.String("zone", zone), zap.String("marker", name), zap.Error(err))
+		}
+		delete(a.lastIPs[zone], managedName)
+		a.logger.Info("deleted stale record", zap.String("zone", zone), zap.String("domain", managedName), zap.String("type", managedType))
+	}
+}
+
+// markerName returns the TXT record name used to mark zone/rrType/name as
+// managed by this module. Names are prefixed with an underscore label, the
+// usual DNS convention for metadata records that aren't meant to resolve.
+func markerName(rrType, name string) string {
+	return "_ddns." + strings.ToLower(rrType) + "." + name
+}
+
+// parseMarkerName reverses markerName, recovering the record type and name
+// it marks. ok is false if marker isn't a name ensureMarker could have
+// produced.
+func parseMarkerName(marker string) (rrType, name string, ok bool) {
+	const prefix = "_ddns."
+	if !strings.HasPrefix(marker, prefix) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(marker, prefix), ".", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return strings.ToUpper(parts[0]), parts[1], true
+}
+
+// reconcileCNAME ensures a single CNAME record matches rec.
+func (a *App) reconcileCNAME(zone string, rec DomainRecord, actual map[string]libdns.Record) {
+	target := rec.Target
+	if target == "" {
+		target = zone + "."
+	}
+
+	have, exists := actual[recordKey("CNAME", rec.Name)]
+	if exists && strings.EqualFold(have.Value, target) && ttlOrDefault(have.TTL) == ttlOrDefault(time.Duration(a.TTL)) {
+		return
 	}
 
-	client, err := ssh.Dial("tcp", a.DNSServer.Host+":22", config)
+	if err := a.DNSServer.backend.EnsureCNAME(zone, rec.Name, target, time.Duration(a.TTL)); err != nil {
+		a.logger.Error("failed updating CNAME record",
+			zap.String("zone", zone), zap.String("domain", rec.Name), zap.Error(err))
+		return
+	}
+	a.logger.Info("updated CNAME record", zap.String("zone", zone), zap.String("domain", rec.Name))
+}
+
+// reconcileAddress ensures a single A or AAAA record matches the address
+// currently reported by rec's ip_source, skipping the backend call entirely
+// when the discovered address matches both the zone's existing record and
+// the address we pushed on the previous pass, and the existing record's TTL
+// matches App.TTL.
+func (a *App) reconcileAddress(zone string, rec DomainRecord, actual map[string]libdns.Record, rrType string) {
+	source, ok := a.IPSources[rec.IPSource]
+	if !ok {
+		a.logger.Error("unknown ip_source",
+			zap.String("zone", zone), zap.String("domain", rec.Name), zap.String("ip_source", rec.IPSource))
+		return
+	}
+
+	src, err := source.build()
 	if err != nil {
-		return fmt.Errorf("failed to dial: %v", err)
+		a.logger.Error("failed to configure ip_source", zap.String("ip_source", rec.IPSource), zap.Error(err))
+		return
 	}
-	defer client.Close()
 
-	session, err := client.NewSession()
+	ip, err := src.Lookup()
 	if err != nil {
-		return fmt.Errorf("failed to create session: %v", err)
+		a.logger.Error("failed to discover address",
+			zap.String("zone", zone), zap.String("domain", rec.Name), zap.Error(err))
+		return
 	}
-	defer session.Close()
+	address := ip.String()
 
-	cmd := fmt.Sprintf("Add-DnsServerResourceRecordCName -ZoneName %s -Name %s -HostNameAlias %s", zone, domain, zone)
-	fullCmd := fmt.Sprintf("powershell -Command \"%s\"", cmd)
+	if a.lastIPs[zone] == nil {
+		a.lastIPs[zone] = make(map[string]string)
+	}
+
+	have, exists := actual[recordKey(rrType, rec.Name)]
+	if exists && strings.EqualFold(have.Value, address) && a.lastIPs[zone][rec.Name] == address &&
+		ttlOrDefault(have.TTL) == ttlOrDefault(time.Duration(a.TTL)) {
+		return
+	}
+
+	var ensureErr error
+	if rrType == "AAAA" {
+		ensureErr = a.DNSServer.backend.EnsureAAAA(zone, rec.Name, address, time.Duration(a.TTL))
+	} else {
+		ensureErr = a.DNSServer.backend.EnsureA(zone, rec.Name, address, time.Duration(a.TTL))
+	}
+	if ensureErr != nil {
+		a.logger.Error("failed updating address record",
+			zap.String("zone", zone), zap.String("domain", rec.Name), zap.String("type", rrType), zap.Error(ensureErr))
+		return
+	}
 
-	output, err := session.CombinedOutput(fullCmd)
+	a.lastIPs[zone][rec.Name] = address
+	a.logger.Info("updated address record",
+		zap.String("zone", zone), zap.String("domain", rec.Name), zap.String("type", rrType), zap.String("address", address))
+}
+
+// listRecords returns the zone's existing records, keyed by "<type>/<name>".
+func (a *App) listRecords(zone string) (map[string]libdns.Record, error) {
+	recs, err := a.DNSServer.backend.ListRecords(zone)
 	if err != nil {
-		return fmt.Errorf("failed to run command: %v, output: %s", err, string(output))
+		return nil, err
 	}
 
-	if strings.Contains(string(output), "Error") {
-		return fmt.Errorf("DNS record update failed: %s", string(output))
+	byKey := make(map[string]libdns.Record, len(recs))
+	for _, rec := range recs {
+		byKey[recordKey(rec.Type, rec.Name)] = rec
 	}
+	return byKey, nil
+}
 
-	return nil
+func recordKey(rrType, name string) string {
+	return strings.ToUpper(rrType) + "/" + name
+}
+
+func splitRecordKey(key string) (rrType, name string) {
+	parts := strings.SplitN(key, "/", 2)
+	if len(parts) != 2 {
+		return "", key
+	}
+	return parts[0], parts[1]
 }
 
-func (a *App) allDomains() map[string][]string {
+func (a *App) allDomains() map[string][]DomainRecord {
 	return a.Domains
 }
 
@@ -202,9 +458,12 @@ func (a *App) addReverseProxyCNAMEs() error {
 								for _, host := range hostMatcher {
 									// Add this host to our domains
 									if a.Domains == nil {
-										a.Domains = make(map[string][]string)
+										a.Domains = make(map[string][]DomainRecord)
 									}
-									a.Domains[a.AutoCNAMEZone] = append(a.Domains[a.AutoCNAMEZone], strings.TrimSuffix(host, "."+a.AutoCNAMEZone))
+									a.Domains[a.AutoCNAMEZone] = append(a.Domains[a.AutoCNAMEZone], DomainRecord{
+										Name: strings.TrimSuffix(host, "."+a.AutoCNAMEZone),
+										Type: "CNAME",
+									})
 								}
 							}
 						}
@@ -219,6 +478,17 @@ func (a *App) addReverseProxyCNAMEs() error {
 
 const defaultCheckInterval = 30 * time.Minute
 
+// ttlOrDefault normalizes a zero/negative TTL to the 1h fallback the
+// backends themselves use when none is configured (see ttlOrDefault in
+// backends/ssh), so comparing a freshly-discovered record's TTL against
+// App.TTL doesn't flag a difference where there isn't one.
+func ttlOrDefault(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
 // Interface guards
 var (
 	_ caddy.Provisioner = (*App)(nil)
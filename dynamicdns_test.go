@@ -0,0 +1,164 @@
+package dynamicdns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"go.uber.org/zap"
+)
+
+// fakeBackend is an in-memory Backend used to exercise reconcileZone without
+// talking to a real DNS server.
+type fakeBackend struct {
+	records map[string]map[string]libdns.Record // zone -> "<type>/<name>" -> record
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{records: make(map[string]map[string]libdns.Record)}
+}
+
+func (f *fakeBackend) ensure(zone string, rec libdns.Record) error {
+	if f.records[zone] == nil {
+		f.records[zone] = make(map[string]libdns.Record)
+	}
+	f.records[zone][recordKey(rec.Type, rec.Name)] = rec
+	return nil
+}
+
+func (f *fakeBackend) EnsureCNAME(zone, name, target string, ttl time.Duration) error {
+	return f.ensure(zone, libdns.Record{Type: "CNAME", Name: name, Value: target, TTL: ttl})
+}
+
+func (f *fakeBackend) EnsureTXT(zone, name, value string, ttl time.Duration) error {
+	return f.ensure(zone, libdns.Record{Type: "TXT", Name: name, Value: value, TTL: ttl})
+}
+
+func (f *fakeBackend) EnsureA(zone, name, address string, ttl time.Duration) error {
+	return f.ensure(zone, libdns.Record{Type: "A", Name: name, Value: address, TTL: ttl})
+}
+
+func (f *fakeBackend) EnsureAAAA(zone, name, address string, ttl time.Duration) error {
+	return f.ensure(zone, libdns.Record{Type: "AAAA", Name: name, Value: address, TTL: ttl})
+}
+
+func (f *fakeBackend) DeleteRecord(zone, name, rrType string) error {
+	delete(f.records[zone], recordKey(rrType, name))
+	return nil
+}
+
+func (f *fakeBackend) ListRecords(zone string) ([]libdns.Record, error) {
+	var recs []libdns.Record
+	for _, rec := range f.records[zone] {
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+func newTestApp(backend Backend) *App {
+	return &App{
+		DNSServer: Provider{backend: backend},
+		logger:    zap.NewNop(),
+	}
+}
+
+func TestReconcileZoneCreatesRecordAndMarker(t *testing.T) {
+	backend := newFakeBackend()
+	a := newTestApp(backend)
+
+	err := a.reconcileZone("example.com", []DomainRecord{{Name: "www", Type: "CNAME", Target: "example.com."}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	recs, _ := backend.ListRecords("example.com")
+	byKey := make(map[string]libdns.Record)
+	for _, r := range recs {
+		byKey[recordKey(r.Type, r.Name)] = r
+	}
+
+	cname, ok := byKey[recordKey("CNAME", "www")]
+	if !ok || cname.Value != "example.com." {
+		t.Errorf("expected CNAME www -> example.com., got %+v", byKey)
+	}
+
+	marker, ok := byKey[recordKey("TXT", markerName("CNAME", "www"))]
+	if !ok || marker.Value != dynamicDNSMarkerValue {
+		t.Errorf("expected an ownership marker for www, got %+v", byKey)
+	}
+}
+
+func TestReconcileZoneRemovesStaleRecord(t *testing.T) {
+	backend := newFakeBackend()
+	a := newTestApp(backend)
+
+	// First pass: "www" is desired and gets created.
+	if err := a.reconcileZone("example.com", []DomainRecord{{Name: "www", Type: "CNAME", Target: "example.com."}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Second pass, as if after a config reload that dropped "www": a fresh
+	// App (no in-process memory of the first pass) should still see the
+	// marker left behind on the server and delete both it and the record.
+	b := newTestApp(backend)
+	if err := b.reconcileZone("example.com", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	recs, _ := backend.ListRecords("example.com")
+	if len(recs) != 0 {
+		t.Errorf("expected all records removed, got %+v", recs)
+	}
+}
+
+func TestReconcileZoneLeavesUnmanagedRecordsAlone(t *testing.T) {
+	backend := newFakeBackend()
+	backend.records["example.com"] = map[string]libdns.Record{
+		recordKey("TXT", "unrelated"): {Type: "TXT", Name: "unrelated", Value: "not ours"},
+	}
+	a := newTestApp(backend)
+
+	if err := a.reconcileZone("example.com", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	recs, _ := backend.ListRecords("example.com")
+	if len(recs) != 1 {
+		t.Errorf("expected the unmanaged TXT record to survive untouched, got %+v", recs)
+	}
+}
+
+func TestMarkerNameRoundTrip(t *testing.T) {
+	rrType, name, ok := parseMarkerName(markerName("CNAME", "www"))
+	if !ok {
+		t.Fatal("expected parseMarkerName to recognize a name produced by markerName")
+	}
+	if rrType != "CNAME" || name != "www" {
+		t.Errorf("got (%s, %s), want (CNAME, www)", rrType, name)
+	}
+}
+
+func TestParseMarkerNameRejectsUnrelatedNames(t *testing.T) {
+	if _, _, ok := parseMarkerName("www"); ok {
+		t.Error("expected a non-marker name to be rejected")
+	}
+}
+
+func TestRecordKeyRoundTrip(t *testing.T) {
+	rrType, name := splitRecordKey(recordKey("cname", "www"))
+	if rrType != "CNAME" || name != "www" {
+		t.Errorf("got (%s, %s), want (CNAME, www)", rrType, name)
+	}
+}
+
+func TestTTLOrDefault(t *testing.T) {
+	if got := ttlOrDefault(0); got != time.Hour {
+		t.Errorf("got %v, want %v for zero TTL", got, time.Hour)
+	}
+	if got := ttlOrDefault(-time.Minute); got != time.Hour {
+		t.Errorf("got %v, want %v for negative TTL", got, time.Hour)
+	}
+	if got := ttlOrDefault(5 * time.Minute); got != 5*time.Minute {
+		t.Errorf("got %v, want unchanged positive TTL", got)
+	}
+}
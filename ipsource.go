@@ -0,0 +1,128 @@
+package dynamicdns
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IPSourceConfig configures one named way of discovering the address to use
+// for an A or AAAA record. A domains entry selects one by name via
+// `ip_source=<name>`.
+type IPSourceConfig struct {
+	// Type is one of "static", "simple_http", or "interface". "upnp" is
+	// also recognized, but build always rejects it: this package has no
+	// UPnP lookup.
+	Type string `json:"type,omitempty"`
+
+	// Address is the address to use, for Type "static".
+	Address string `json:"address,omitempty"`
+
+	// URLs are the plain-text "what's my IP" endpoints to try in order, for
+	// Type "simple_http" (e.g. https://api.ipify.org).
+	URLs []string `json:"urls,omitempty"`
+
+	// Interface is the local network interface to read an address from, for
+	// Type "interface".
+	Interface string `json:"interface,omitempty"`
+}
+
+// ipSource looks up the current address for an IPSourceConfig.
+type ipSource interface {
+	Lookup() (net.IP, error)
+}
+
+func (c IPSourceConfig) build() (ipSource, error) {
+	switch c.Type {
+	case "", "static":
+		ip := net.ParseIP(c.Address)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid static address %q", c.Address)
+		}
+		return staticSource{ip}, nil
+	case "simple_http":
+		if len(c.URLs) == 0 {
+			return nil, fmt.Errorf("simple_http ip_source requires at least one URL")
+		}
+		return simpleHTTPSource{urls: c.URLs}, nil
+	case "interface":
+		if c.Interface == "" {
+			return nil, fmt.Errorf("interface ip_source requires an interface name")
+		}
+		return interfaceSource{name: c.Interface}, nil
+	case "upnp":
+		return nil, fmt.Errorf("upnp ip_source is not implemented yet")
+	default:
+		return nil, fmt.Errorf("unknown ip_source type %q", c.Type)
+	}
+}
+
+type staticSource struct{ ip net.IP }
+
+func (s staticSource) Lookup() (net.IP, error) { return s.ip, nil }
+
+// simpleHTTPSource discovers the public IP by asking one of a list of
+// plain-text "what's my IP" services, trying each in turn until one works.
+type simpleHTTPSource struct{ urls []string }
+
+func (s simpleHTTPSource) Lookup() (net.IP, error) {
+	var lastErr error
+	for _, u := range s.urls {
+		ip, err := fetchIP(u)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return ip, nil
+	}
+	return nil, fmt.Errorf("all simple_http ip_sources failed, last error: %v", lastErr)
+}
+
+func fetchIP(url string) (net.IP, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", url, err)
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return nil, fmt.Errorf("%s: did not return an IP address: %q", url, body)
+	}
+	return ip, nil
+}
+
+// interfaceSource reads the first IPv4 address off a local network
+// interface, for deployments where the Caddy host itself owns the public
+// address (no NAT in front of it).
+type interfaceSource struct{ name string }
+
+func (s interfaceSource) Lookup() (net.IP, error) {
+	iface, err := net.InterfaceByName(s.name)
+	if err != nil {
+		return nil, fmt.Errorf("interface %q: %v", s.name, err)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("interface %q: %v", s.name, err)
+	}
+
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip := ipNet.IP.To4(); ip != nil {
+			return ip, nil
+		}
+	}
+	return nil, fmt.Errorf("interface %q has no IPv4 address", s.name)
+}
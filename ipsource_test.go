@@ -0,0 +1,112 @@
+package dynamicdns
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPSourceConfigBuildStatic(t *testing.T) {
+	cases := []struct {
+		name    string
+		cfg     IPSourceConfig
+		wantErr bool
+		wantIP  string
+	}{
+		{"defaults to static", IPSourceConfig{Address: "1.2.3.4"}, false, "1.2.3.4"},
+		{"explicit static", IPSourceConfig{Type: "static", Address: "::1"}, false, "::1"},
+		{"invalid address", IPSourceConfig{Type: "static", Address: "not-an-ip"}, true, ""},
+	}
+
+	for _, c := range cases {
+		src, err := c.cfg.build()
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got none", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		ip, err := src.Lookup()
+		if err != nil {
+			t.Errorf("%s: unexpected lookup error: %v", c.name, err)
+			continue
+		}
+		if ip.String() != c.wantIP {
+			t.Errorf("%s: got %s, want %s", c.name, ip, c.wantIP)
+		}
+	}
+}
+
+func TestIPSourceConfigBuildSimpleHTTP(t *testing.T) {
+	if _, err := (IPSourceConfig{Type: "simple_http"}).build(); err == nil {
+		t.Error("expected error when no URLs are configured")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("5.6.7.8\n"))
+	}))
+	defer srv.Close()
+
+	src, err := (IPSourceConfig{Type: "simple_http", URLs: []string{srv.URL}}).build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ip, err := src.Lookup()
+	if err != nil {
+		t.Fatalf("unexpected lookup error: %v", err)
+	}
+	if ip.String() != "5.6.7.8" {
+		t.Errorf("got %s, want 5.6.7.8", ip)
+	}
+}
+
+func TestSimpleHTTPSourceFallsBackToNextURL(t *testing.T) {
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not an ip"))
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("9.9.9.9"))
+	}))
+	defer good.Close()
+
+	src := simpleHTTPSource{urls: []string{bad.URL, good.URL}}
+	ip, err := src.Lookup()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ip.String() != "9.9.9.9" {
+		t.Errorf("got %s, want 9.9.9.9", ip)
+	}
+}
+
+func TestIPSourceConfigBuildInterface(t *testing.T) {
+	if _, err := (IPSourceConfig{Type: "interface"}).build(); err == nil {
+		t.Error("expected error when no interface name is configured")
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil || len(ifaces) == 0 {
+		t.Skip("no local interfaces available to test against")
+	}
+	if _, err := (IPSourceConfig{Type: "interface", Interface: ifaces[0].Name}).build(); err != nil {
+		t.Errorf("unexpected error building interface source: %v", err)
+	}
+}
+
+func TestIPSourceConfigBuildUpnpRejected(t *testing.T) {
+	if _, err := (IPSourceConfig{Type: "upnp"}).build(); err == nil {
+		t.Error("expected upnp ip_source to be rejected at build time, since Lookup is not implemented")
+	}
+}
+
+func TestIPSourceConfigBuildUnknownType(t *testing.T) {
+	if _, err := (IPSourceConfig{Type: "carrier_pigeon"}).build(); err == nil {
+		t.Error("expected error for unknown ip_source type")
+	}
+}
@@ -0,0 +1,161 @@
+package dynamicdns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/libdns/libdns"
+	"go.uber.org/zap"
+)
+
+func init() {
+	caddy.RegisterModule(new(Provider))
+}
+
+// Provider implements the libdns interfaces for a Windows DNS Server,
+// loading a Backend module the same way the dynamic_dns app's dns_server
+// block does, so the two share one transport, one auth config, and one
+// implementation (ssh, winrm, or dnscmd). Registering it as a Caddy module
+// lets it be used directly as an ACME DNS-01 solver via:
+//
+//	tls {
+//	    dns windns {
+//	        backend <name> {
+//	            ...
+//	        }
+//	    }
+//	}
+type Provider struct {
+	// BackendRaw is the configuration for how to reach the DNS server, as a
+	// JSON-encoded module (e.g. `{"backend": "ssh", ...}`). Set by the
+	// `backend <name> {...}` Caddyfile subdirective.
+	BackendRaw json.RawMessage `json:"backend,omitempty" caddy:"namespace=dynamic_dns.backends inline_key=backend"`
+
+	backend Backend
+	logger  *zap.Logger
+}
+
+func (Provider) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "dns.providers.windns",
+		New: func() caddy.Module { return new(Provider) },
+	}
+}
+
+// Provision sets up the provider.
+func (p *Provider) Provision(ctx caddy.Context) error {
+	p.logger = ctx.Logger(p)
+
+	backendIface, err := ctx.LoadModule(p, "BackendRaw")
+	if err != nil {
+		return fmt.Errorf("loading backend module: %v", err)
+	}
+	backend, ok := backendIface.(Backend)
+	if !ok {
+		return fmt.Errorf("configured backend does not implement dynamicdns.Backend")
+	}
+	p.backend = backend
+
+	return nil
+}
+
+// UnmarshalCaddyfile sets up the provider from Caddyfile tokens. Syntax:
+//
+//	windns {
+//	    backend <name> {
+//	        ...
+//	    }
+//	}
+func (p *Provider) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	for d.Next() {
+		for nesting := d.Nesting(); d.NextBlock(nesting); {
+			switch d.Val() {
+			case "backend":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				name := d.Val()
+				unm, err := caddyfile.UnmarshalModule(d, "dynamic_dns.backends."+name)
+				if err != nil {
+					return err
+				}
+				p.BackendRaw = caddyconfig.JSONModuleObject(unm, "backend", name, nil)
+			default:
+				return d.Errf("unrecognized subdirective '%s'", d.Val())
+			}
+		}
+	}
+	return nil
+}
+
+// AppendRecords adds the given records to the zone and returns the records
+// that were added.
+func (p *Provider) AppendRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	var added []libdns.Record
+	for _, rec := range recs {
+		if err := p.ensure(zone, rec); err != nil {
+			return added, err
+		}
+		added = append(added, rec)
+	}
+	return added, nil
+}
+
+// SetRecords sets the given records in the zone, either by adding them if
+// they don't already exist or updating them in place if they do, and
+// returns the records that were set.
+func (p *Provider) SetRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	var set []libdns.Record
+	for _, rec := range recs {
+		if err := p.ensure(zone, rec); err != nil {
+			return set, err
+		}
+		set = append(set, rec)
+	}
+	return set, nil
+}
+
+// DeleteRecords removes the given records from the zone and returns the
+// records that were removed.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	var deleted []libdns.Record
+	for _, rec := range recs {
+		if err := p.backend.DeleteRecord(zone, rec.Name, rec.Type); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, rec)
+	}
+	return deleted, nil
+}
+
+// GetRecords lists all the records in the zone.
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	return p.backend.ListRecords(zone)
+}
+
+func (p *Provider) ensure(zone string, rec libdns.Record) error {
+	ttl := ttlOrDefault(rec.TTL)
+
+	switch rec.Type {
+	case "CNAME":
+		return p.backend.EnsureCNAME(zone, rec.Name, rec.Value, ttl)
+	case "TXT":
+		return p.backend.EnsureTXT(zone, rec.Name, rec.Value, ttl)
+	default:
+		return fmt.Errorf("unsupported record type: %s", rec.Type)
+	}
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner     = (*Provider)(nil)
+	_ caddyfile.Unmarshaler = (*Provider)(nil)
+	_ libdns.RecordGetter   = (*Provider)(nil)
+	_ libdns.RecordAppender = (*Provider)(nil)
+	_ libdns.RecordSetter   = (*Provider)(nil)
+	_ libdns.RecordDeleter  = (*Provider)(nil)
+)